@@ -1,16 +1,24 @@
 package server
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/etcd/third_party/github.com/goraft/raft"
+	"github.com/coreos/etcd/third_party/golang.org/x/net/context"
 
 	etcdErr "github.com/coreos/etcd/error"
 	"github.com/coreos/etcd/log"
@@ -20,19 +28,94 @@ import (
 
 const standbyInfoName = "standby_info"
 
+// standbyInfoBakSuffix/standbyInfoTmpSuffix name the backup and in-progress
+// copies saveStandbyInfo uses for its write-temp-then-rename pattern.
+const (
+	standbyInfoBakSuffix = ".bak"
+	standbyInfoTmpSuffix = ".tmp"
+)
+
+// crcTrailerSize is the size in bytes of the CRC32 checksum saveStandbyInfo
+// appends after the JSON payload.
+const crcTrailerSize = 4
+
+// backoffRand is a process-local random source for nextBackoff's jitter.
+// Using it instead of the shared math/rand default source matters here:
+// on toolchains that don't auto-seed that source, every standby process
+// would otherwise produce the exact same jitter sequence, putting nodes
+// that start around the same time right back in lockstep.
+var (
+	backoffRandMu sync.Mutex
+	backoffRand   = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())))
+)
+
+// randInt63n is a concurrency-safe wrapper around backoffRand.Int63n, since
+// multiple StandbyServer instances in one process call nextBackoff from
+// their own goroutines.
+func randInt63n(n int64) int64 {
+	backoffRandMu.Lock()
+	defer backoffRandMu.Unlock()
+	return backoffRand.Int63n(n)
+}
+
+// ProxyMode controls how a StandbyServer handles client requests while it
+// is waiting to join the cluster as a full peer.
+type ProxyMode string
+
+const (
+	// ProxyModeRedirect forwards every client request to the cluster
+	// leader. This is the original standby behavior.
+	ProxyModeRedirect ProxyMode = "redirect"
+
+	// ProxyModeReadonly serves plain GET requests from a local store kept
+	// in sync with the leader's event log, and redirects writes and
+	// watches.
+	ProxyModeReadonly ProxyMode = "readonly"
+
+	// ProxyModeFollower keeps the full store state locally by replaying
+	// the leader's event log, and redirects writes.
+	ProxyModeFollower ProxyMode = "follower"
+)
+
+// DefaultProxyMode is used when StandbyServerConfig.ProxyMode is empty.
+const DefaultProxyMode = ProxyModeRedirect
+
 type StandbyServerConfig struct {
 	Name       string
 	PeerScheme string
 	PeerURL    string
 	ClientURL  string
 	DataDir    string
+	ProxyMode  ProxyMode
 }
 
 type standbyInfo struct {
 	Cluster      []*machineMessage
 	SyncInterval float64
+	PeerHealth   map[string]*peerHealth `json:"peerHealth,omitempty"`
 }
 
+// peerHealth tracks a peer's recent join-attempt history so syncCluster can
+// prefer healthy peers and quarantine ones that are repeatedly unreachable.
+// It is persisted as part of standbyInfo so quarantines survive restarts.
+type peerHealth struct {
+	ConsecutiveFailures int
+	QuarantinedUntil    time.Time
+}
+
+const (
+	// quarantinePeerThreshold is the number of consecutive failures
+	// against a peer before syncCluster quarantines it.
+	quarantinePeerThreshold = 3
+
+	// quarantineCooldown is how long a quarantined peer is skipped before
+	// syncCluster retries it.
+	quarantineCooldown = 5 * time.Minute
+
+	// maxMonitorBackoff caps the delay between monitorCluster retries.
+	maxMonitorBackoff = 30 * time.Second
+)
+
 type StandbyServer struct {
 	Config StandbyServerConfig
 	client *Client
@@ -40,6 +123,21 @@ type StandbyServer struct {
 	standbyInfo
 	joinIndex uint64
 
+	// localStore mirrors the leader's keyspace via replicateFromLeader so
+	// that reads can be served without a redirect round-trip. It is nil
+	// when Config.ProxyMode is ProxyModeRedirect.
+	localStore store.Store
+
+	// localStoreReady is 1 once localStore has been seeded with a full
+	// snapshot from the leader (see seedLocalStore) and is safe to answer
+	// reads from; 0 while it would still be missing pre-existing keys.
+	localStoreReady int32
+
+	// backoff is the current monitorCluster retry interval. It grows with
+	// decorrelated jitter on each failed attempt and is reset once the
+	// standby successfully joins the cluster.
+	backoff time.Duration
+
 	file     *os.File
 	recorded bool
 
@@ -48,6 +146,20 @@ type StandbyServer struct {
 	closeChan    chan bool
 	routineGroup sync.WaitGroup
 
+	// quiesceDone is set by Promote the first time it starts draining the
+	// background goroutines, so a retried Promote call (after a prior call
+	// timed out on ctx) waits on the same drain instead of racing a second
+	// one or skipping it entirely now that started is already false.
+	quiesceDone chan struct{}
+
+	// healthMu guards PeerHealth, which SyncCluster and the internal
+	// monitorCluster goroutine can both update concurrently. It is
+	// separate from the embedded Mutex because Stop/Promote hold that one
+	// for the entire time they wait on routineGroup, which would deadlock
+	// against a background goroutine blocked acquiring it to record a
+	// peer's health.
+	healthMu sync.Mutex
+
 	sync.Mutex
 }
 
@@ -75,6 +187,16 @@ func (s *StandbyServer) Start() {
 	s.removeNotify = make(chan bool)
 	s.closeChan = make(chan bool)
 
+	if s.proxyMode() != ProxyModeRedirect {
+		s.localStore = store.New()
+		atomic.StoreInt32(&s.localStoreReady, 0)
+		s.routineGroup.Add(1)
+		go func() {
+			defer s.routineGroup.Done()
+			s.replicateFromLeader()
+		}()
+	}
+
 	s.routineGroup.Add(1)
 	go func() {
 		defer s.routineGroup.Done()
@@ -105,8 +227,110 @@ func (s *StandbyServer) RemoveNotify() <-chan bool {
 	return s.removeNotify
 }
 
+// Promote hands this standby's join index and data directory file handle
+// off to a freshly constructed PeerServer, so the switch from standby to
+// full peer happens in-process instead of going through an outer
+// supervisor that tears the standby down and starts a new process. It must
+// only be called after a successful join. ctx bounds how long Promote
+// waits for the standby's background goroutines to drain before handing
+// off.
+func (s *StandbyServer) Promote(ctx context.Context) (*PeerServer, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.joinIndex == 0 {
+		return nil, fmt.Errorf("standby server has not joined the cluster yet")
+	}
+
+	// Quiesce the background goroutines first: monitorCluster/syncCluster
+	// can still be reassigning s.file via saveStandbyInfo until then, so
+	// reading s.file before this point races with it.
+	if s.started {
+		// Flip started and close closeChan before the blocking wait below:
+		// if ctx fires first and we return early, a later Promote/Stop
+		// call must still see both as already done instead of closing
+		// closeChan again.
+		s.started = false
+		close(s.closeChan)
+		s.quiesceDone = make(chan struct{})
+		go func() {
+			s.routineGroup.Wait()
+			close(s.quiesceDone)
+		}()
+	}
+
+	if s.quiesceDone != nil {
+		select {
+		case <-s.quiesceDone:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	peerServer, err := NewPeerServer(PeerServerConfig{
+		Name:    s.Config.Name,
+		Scheme:  s.Config.PeerScheme,
+		URL:     s.Config.PeerURL,
+		DataDir: s.Config.DataDir,
+	}, s.file, s.joinIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// The info file now belongs to peerServer; don't let Stop/openStandbyInfo
+	// touch it again.
+	s.file = nil
+
+	return peerServer, nil
+}
+
 func (s *StandbyServer) ClientHTTPHandler() http.Handler {
-	return http.HandlerFunc(s.redirectRequests)
+	return http.HandlerFunc(s.serveClientRequest)
+}
+
+// proxyMode returns the configured ProxyMode, defaulting to
+// ProxyModeRedirect when none was set.
+func (s *StandbyServer) proxyMode() ProxyMode {
+	if s.Config.ProxyMode == "" {
+		return DefaultProxyMode
+	}
+	return s.Config.ProxyMode
+}
+
+// serveClientRequest answers reads from the local store when running in
+// readonly or follower proxy mode, and redirects everything else to the
+// cluster leader. Only follower mode answers watches from the local store;
+// readonly mode forwards them like writes, so in that mode a watch is always
+// served by the leader. Reads redirect
+// too until localStoreReady flips, so a standby that hasn't finished its
+// initial snapshot yet doesn't serve false not-found responses.
+func (s *StandbyServer) serveClientRequest(w http.ResponseWriter, r *http.Request) {
+	if s.localStore == nil || atomic.LoadInt32(&s.localStoreReady) == 0 || isWriteRequest(r) {
+		s.redirectRequests(w, r)
+		return
+	}
+	if isWatchRequest(r) && s.proxyMode() != ProxyModeFollower {
+		s.redirectRequests(w, r)
+		return
+	}
+	s.serveLocalRead(w, r)
+}
+
+// isWriteRequest reports whether r mutates the keyspace and therefore must
+// be forwarded to the cluster leader rather than answered locally.
+func isWriteRequest(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD":
+		return false
+	default:
+		return true
+	}
+}
+
+// isWatchRequest reports whether r is a long-polling watch rather than a
+// plain point-in-time read.
+func isWatchRequest(r *http.Request) bool {
+	return r.URL.Query().Get("wait") == "true"
 }
 
 func (s *StandbyServer) ClusterRecorded() bool {
@@ -170,11 +394,230 @@ func (s *StandbyServer) redirectRequests(w http.ResponseWriter, r *http.Request)
 	uhttp.Redirect(leader.ClientURL, w, r)
 }
 
+// serveLocalRead answers a read or (follower-mode only) watch request out
+// of the local store instead of redirecting it to the leader.
+func (s *StandbyServer) serveLocalRead(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v2/keys")
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	if isWatchRequest(r) {
+		var sinceIndex uint64
+		if v := r.URL.Query().Get("waitIndex"); v != "" {
+			if parsed, err := strconv.ParseUint(v, 10, 64); err == nil {
+				sinceIndex = parsed
+			}
+		}
+		s.serveLocalWatch(w, key, recursive, sinceIndex)
+		return
+	}
+
+	sorted := r.URL.Query().Get("sorted") == "true"
+	event, err := s.localStore.Get(key, recursive, sorted)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.Header().Set("X-Etcd-Index", fmt.Sprint(s.localStore.Index()))
+		etcdErr.NewError(etcdErr.EcodeKeyNotFound, key, s.localStore.Index()).Write(w)
+		return
+	}
+
+	w.Header().Set("X-Etcd-Index", fmt.Sprint(event.Index))
+	json.NewEncoder(w).Encode(event)
+}
+
+// serveLocalWatch blocks until the local store (kept current by
+// replicateFromLeader) produces a matching event, or the standby is
+// stopped, so a watch routed to a follower-mode standby gets the same
+// blocking semantics it would from the leader instead of an immediate
+// snapshot.
+func (s *StandbyServer) serveLocalWatch(w http.ResponseWriter, key string, recursive bool, sinceIndex uint64) {
+	w.Header().Set("Content-Type", "application/json")
+
+	watcher, err := s.localStore.Watch(key, recursive, false, sinceIndex)
+	if err != nil {
+		etcdErr.NewError(etcdErr.EcodeEventIndexCleared, key, s.localStore.Index()).Write(w)
+		return
+	}
+
+	select {
+	case event := <-watcher.EventChan:
+		w.Header().Set("X-Etcd-Index", fmt.Sprint(event.Index))
+		json.NewEncoder(w).Encode(event)
+	case <-s.closeChan:
+		watcher.Remove()
+	}
+}
+
+// replicateFromLeader keeps localStore in sync with the cluster leader: it
+// loads a full snapshot once before localStore is trusted for reads, then
+// streams the event log, reconnecting with the current leader whenever the
+// stream breaks.
+func (s *StandbyServer) replicateFromLeader() {
+	for {
+		select {
+		case <-s.closeChan:
+			return
+		default:
+		}
+
+		leader := s.ClusterLeader()
+		if leader == nil {
+			time.Sleep(time.Duration(int64(s.SyncInterval * float64(time.Second))))
+			continue
+		}
+
+		if atomic.LoadInt32(&s.localStoreReady) == 0 {
+			if err := s.seedLocalStore(leader.PeerURL); err != nil {
+				log.Warnf("standby: failed loading snapshot from %v: %v", leader.PeerURL, err)
+				select {
+				case <-s.closeChan:
+					return
+				case <-time.After(time.Duration(int64(s.SyncInterval * float64(time.Second)))):
+				}
+				continue
+			}
+			atomic.StoreInt32(&s.localStoreReady, 1)
+		}
+
+		if err := s.streamEvents(leader.PeerURL); err != nil {
+			log.Debugf("standby: event stream from %v stopped: %v", leader.PeerURL, err)
+		}
+
+		select {
+		case <-s.closeChan:
+			return
+		case <-time.After(time.Duration(int64(s.SyncInterval * float64(time.Second)))):
+		}
+	}
+}
+
+// seedLocalStore performs a single recursive read of the leader's entire
+// keyspace and loads it into localStore. Without this, localStore would
+// only ever contain keys that were created or changed after the standby
+// started streaming, and reads for older, untouched keys would wrongly
+// come back as not-found.
+func (s *StandbyServer) seedLocalStore(peerURL string) error {
+	resp, err := http.Get(fmt.Sprintf("%s/v2/keys/?recursive=true", peerURL))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("standby: leader %v returned %v fetching snapshot", peerURL, resp.Status)
+	}
+
+	var event store.Event
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return err
+	}
+
+	return s.applySnapshotNode(event.Node)
+}
+
+// applySnapshotNode loads a snapshot node and its children into localStore.
+func (s *StandbyServer) applySnapshotNode(node *store.NodeExtern) error {
+	if node == nil {
+		return nil
+	}
+	if !node.Dir {
+		var expireTime time.Time
+		if node.Expiration != nil {
+			expireTime = *node.Expiration
+		}
+		if _, err := s.localStore.Set(node.Key, false, node.Value, expireTime); err != nil {
+			return err
+		}
+	}
+	for _, child := range node.Nodes {
+		if err := s.applySnapshotNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamEvents long-polls peerURL's event log starting at s.joinIndex and
+// applies every event it receives to localStore until the connection drops
+// or the standby is stopped. Each request is tied to s.closeChan via
+// req.Cancel so Stop/Promote can interrupt an in-flight long poll instead of
+// hanging on it forever.
+func (s *StandbyServer) streamEvents(peerURL string) error {
+	waitIndex := s.joinIndex
+	client := &http.Client{}
+
+	for {
+		select {
+		case <-s.closeChan:
+			return nil
+		default:
+		}
+
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/v2/keys/?wait=true&recursive=true&waitIndex=%d", peerURL, waitIndex), nil)
+		if err != nil {
+			return err
+		}
+
+		cancel := make(chan struct{})
+		reqDone := make(chan struct{})
+		req.Cancel = cancel
+		go func() {
+			select {
+			case <-s.closeChan:
+				close(cancel)
+			case <-reqDone:
+			}
+		}()
+
+		resp, err := client.Do(req)
+		close(reqDone)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode/100 != 2 {
+			resp.Body.Close()
+			return fmt.Errorf("standby: leader %v returned %v watching event log", peerURL, resp.Status)
+		}
+
+		var event store.Event
+		err = json.NewDecoder(resp.Body).Decode(&event)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if err := s.applyEvent(&event); err != nil {
+			log.Debugf("standby: failed applying event %v to local store: %v", event.Index, err)
+		}
+
+		waitIndex = event.Index + 1
+	}
+}
+
+// applyEvent replays a single leader event against localStore.
+func (s *StandbyServer) applyEvent(event *store.Event) error {
+	node := event.Node
+	switch event.Action {
+	case store.Delete, store.Expire, store.CompareAndDelete:
+		_, err := s.localStore.Delete(node.Key, node.Dir, true)
+		return err
+	default:
+		var expireTime time.Time
+		if node.Expiration != nil {
+			expireTime = *node.Expiration
+		}
+		_, err := s.localStore.Set(node.Key, node.Dir, node.Value, expireTime)
+		return err
+	}
+}
+
 // monitorCluster assumes that the machine has tried to join the cluster and
-// failed, so it waits for the interval at the beginning.
+// failed, so it waits for the interval at the beginning. The interval grows
+// with decorrelated jitter on repeated failures so many standbys coming up
+// at once don't retry in lockstep or hammer a dead peer.
 func (s *StandbyServer) monitorCluster() {
 	for {
-		timer := time.NewTimer(time.Duration(int64(s.SyncInterval * float64(time.Second))))
+		timer := time.NewTimer(s.nextBackoff())
 		defer timer.Stop()
 		select {
 		case <-s.closeChan:
@@ -198,35 +641,67 @@ func (s *StandbyServer) monitorCluster() {
 			continue
 		}
 
+		s.backoff = 0
 		log.Infof("join through leader %v", leader.PeerURL)
-		go func() {
-			s.Stop()
-			close(s.removeNotify)
-		}()
+		// Leave teardown to whoever observes RemoveNotify: Promote needs
+		// the standby_info file and data directory still intact to hand
+		// off to the new PeerServer, which an automatic Stop() here would
+		// have already deleted. A caller that isn't promoting is expected
+		// to call Stop() itself.
+		close(s.removeNotify)
 		return
 	}
 }
 
+// nextBackoff returns the delay before the next monitorCluster attempt,
+// using decorrelated jitter (sleep = random(base, 3*prev), capped at
+// maxMonitorBackoff) so it grows on repeated failures without synchronizing
+// across standbys and resets back to the base SyncInterval once s.backoff
+// is cleared after a successful join.
+func (s *StandbyServer) nextBackoff() time.Duration {
+	base := time.Duration(int64(s.SyncInterval * float64(time.Second)))
+	if s.backoff < base {
+		s.backoff = base
+	}
+	next := base + time.Duration(randInt63n(int64(s.backoff*3-base+1)))
+	if next > maxMonitorBackoff {
+		next = maxMonitorBackoff
+	}
+	s.backoff = next
+	return next
+}
+
 func (s *StandbyServer) syncCluster(peerURLs []string) error {
-	peerURLs = append(s.ClusterURLs(), peerURLs...)
+	peerURLs = s.healthyPeerURLs(append(s.ClusterURLs(), peerURLs...))
 
 	for _, peerURL := range peerURLs {
 		// Fetch current peer list
 		machines, err := s.client.GetMachines(peerURL)
 		if err != nil {
 			log.Debugf("fail getting machine messages from %v", peerURL)
+			s.recordPeerFailure(peerURL)
 			continue
 		}
 
 		config, err := s.client.GetClusterConfig(peerURL)
 		if err != nil {
 			log.Debugf("fail getting cluster config from %v", peerURL)
+			s.recordPeerFailure(peerURL)
 			continue
 		}
 
+		s.recordPeerSuccess(peerURL)
 		s.setCluster(machines)
 		s.SetSyncInterval(config.SyncInterval)
-		if err := s.saveStandbyInfo(); err != nil {
+
+		// saveStandbyInfo marshals s.standbyInfo, which embeds PeerHealth;
+		// take healthMu here too so this doesn't race recordPeerFailure/
+		// recordPeerSuccess/healthyPeerURLs running from a concurrent
+		// syncCluster call (e.g. via the exported SyncCluster).
+		s.healthMu.Lock()
+		err = s.saveStandbyInfo()
+		s.healthMu.Unlock()
+		if err != nil {
 			log.Warnf("fail saving cluster info into disk: %v", err)
 		}
 		return nil
@@ -234,6 +709,66 @@ func (s *StandbyServer) syncCluster(peerURLs []string) error {
 	return fmt.Errorf("unreachable cluster")
 }
 
+// recordPeerFailure bumps peerURL's consecutive failure count, quarantining
+// it once the count reaches quarantinePeerThreshold, and persists the
+// result so the quarantine survives a restart.
+func (s *StandbyServer) recordPeerFailure(peerURL string) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if s.PeerHealth == nil {
+		s.PeerHealth = make(map[string]*peerHealth)
+	}
+	h, ok := s.PeerHealth[peerURL]
+	if !ok {
+		h = &peerHealth{}
+		s.PeerHealth[peerURL] = h
+	}
+	h.ConsecutiveFailures++
+	if h.ConsecutiveFailures >= quarantinePeerThreshold {
+		h.QuarantinedUntil = time.Now().Add(quarantineCooldown)
+	}
+	if err := s.saveStandbyInfo(); err != nil {
+		log.Warnf("fail saving cluster info into disk: %v", err)
+	}
+}
+
+// recordPeerSuccess clears peerURL's failure history.
+func (s *StandbyServer) recordPeerSuccess(peerURL string) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if _, ok := s.PeerHealth[peerURL]; !ok {
+		return
+	}
+	delete(s.PeerHealth, peerURL)
+	if err := s.saveStandbyInfo(); err != nil {
+		log.Warnf("fail saving cluster info into disk: %v", err)
+	}
+}
+
+// healthyPeerURLs reorders peerURLs so recently-healthy peers are tried
+// first; peers still within their quarantine cooldown are moved to the end
+// rather than dropped, so the standby can still recover if every known peer
+// happens to be quarantined.
+func (s *StandbyServer) healthyPeerURLs(peerURLs []string) []string {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	now := time.Now()
+	healthy := make([]string, 0, len(peerURLs))
+	quarantined := make([]string, 0)
+
+	for _, peerURL := range peerURLs {
+		if h := s.PeerHealth[peerURL]; h != nil && now.Before(h.QuarantinedUntil) {
+			quarantined = append(quarantined, peerURL)
+			continue
+		}
+		healthy = append(healthy, peerURL)
+	}
+	return append(healthy, quarantined...)
+}
+
 func (s *StandbyServer) join(peer string) error {
 	// Our version must match the leaders version
 	version, err := s.client.GetVersion(peer)
@@ -284,49 +819,151 @@ func (s *StandbyServer) fullPeerURL(urlStr string) string {
 	return u.String()
 }
 
+func (s *StandbyServer) standbyInfoPath() string {
+	return filepath.Join(s.Config.DataDir, standbyInfoName)
+}
+
 func (s *StandbyServer) openStandbyInfo() error {
 	var err error
-	path := filepath.Join(s.Config.DataDir, standbyInfoName)
-	s.file, err = os.OpenFile(path, os.O_RDWR, 0600)
+	s.file, err = os.OpenFile(s.standbyInfoPath(), os.O_RDWR|os.O_CREATE, 0600)
+	return err
+}
+
+// loadStandbyInfo reads the persisted standby info, verifying the CRC32
+// trailer saveStandbyInfo writes after the JSON payload. A missing or empty
+// primary copy means nothing has been recorded yet and loads nothing. If the
+// primary copy fails verification (e.g. a half-written file left by a
+// crash), it falls back to the ".bak" copy of the last known-good write
+// before giving up.
+func (s *StandbyServer) loadStandbyInfo() ([]*machineMessage, error) {
+	data, err := ioutil.ReadFile(s.standbyInfoPath())
 	if err != nil {
 		if os.IsNotExist(err) {
-			s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
-	return nil
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	info, err := decodeStandbyInfo(data)
+	if err != nil {
+		log.Warnf("standby info failed verification, falling back to backup: %v", err)
+		bakData, bakErr := ioutil.ReadFile(s.standbyInfoPath() + standbyInfoBakSuffix)
+		if bakErr != nil {
+			log.Warnf("standby info backup unavailable (%v); standby has lost its cluster membership and must rejoin from scratch", bakErr)
+			return nil, err
+		}
+		info, err = decodeStandbyInfo(bakData)
+		if err != nil {
+			log.Warnf("standby info backup also failed verification (%v); standby has lost its cluster membership and must rejoin from scratch", err)
+			return nil, err
+		}
+	}
+
+	s.standbyInfo = *info
+	s.recorded = true
+	return s.standbyInfo.Cluster, nil
 }
 
-func (s *StandbyServer) loadStandbyInfo() ([]*machineMessage, error) {
-	if _, err := s.file.Seek(0, os.SEEK_SET); err != nil {
-		return nil, err
+// decodeStandbyInfo verifies the CRC32 trailer written by saveStandbyInfo
+// and decodes the JSON payload it protects.
+func decodeStandbyInfo(data []byte) (*standbyInfo, error) {
+	if len(data) < crcTrailerSize {
+		return nil, fmt.Errorf("standby info is truncated")
+	}
+
+	payload := data[:len(data)-crcTrailerSize]
+	wantCRC := binary.BigEndian.Uint32(data[len(data)-crcTrailerSize:])
+	if gotCRC := crc32.ChecksumIEEE(payload); gotCRC != wantCRC {
+		return nil, fmt.Errorf("standby info failed checksum verification")
 	}
-	if err := json.NewDecoder(s.file).Decode(&s.standbyInfo); err != nil {
+
+	info := &standbyInfo{}
+	if err := json.Unmarshal(payload, info); err != nil {
 		return nil, err
 	}
-	s.recorded = true
-	return s.standbyInfo.Cluster, nil
+	return info, nil
 }
 
+// saveStandbyInfo durably persists standbyInfo. It JSON-encodes the payload
+// with a CRC32 trailer, writes it to a temp file in the same directory,
+// fsyncs the temp file, backs up the previous good copy, and only then
+// renames the temp file into place and fsyncs the directory. A crash at any
+// point during this sequence leaves either the old copy or the fully
+// written new copy on disk, never a half-written one.
 func (s *StandbyServer) saveStandbyInfo() error {
-	if err := s.clearStandbyInfo(); err != nil {
-		return nil
+	payload, err := json.Marshal(s.standbyInfo)
+	if err != nil {
+		return err
 	}
-	if err := json.NewEncoder(s.file).Encode(s.standbyInfo); err != nil {
+
+	var trailer [crcTrailerSize]byte
+	binary.BigEndian.PutUint32(trailer[:], crc32.ChecksumIEEE(payload))
+
+	path := s.standbyInfoPath()
+	tmpPath := path + standbyInfoTmpSuffix
+
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
 		return err
 	}
-	if err := s.file.Sync(); err != nil {
+	if _, err := tmp.Write(append(payload, trailer[:]...)); err != nil {
+		tmp.Close()
 		return err
 	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if prev, err := ioutil.ReadFile(path); err == nil && len(prev) > 0 {
+		if err := ioutil.WriteFile(path+standbyInfoBakSuffix, prev, 0600); err != nil {
+			log.Warnf("fail backing up standby info: %v", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		log.Warnf("fail fsyncing standby info directory: %v", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	if s.file, err = os.OpenFile(path, os.O_RDWR, 0600); err != nil {
+		return err
+	}
+
 	s.recorded = true
 	return nil
 }
 
+// syncDir fsyncs a directory so that a preceding rename into it is durable
+// across a crash, not just visible.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// clearStandbyInfo removes the persisted standby info and its backup so a
+// freshly started standby doesn't see stale cluster membership.
 func (s *StandbyServer) clearStandbyInfo() error {
-	if _, err := s.file.Seek(0, os.SEEK_SET); err != nil {
+	path := s.standbyInfoPath()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	if err := s.file.Truncate(0); err != nil {
+	if err := os.Remove(path + standbyInfoBakSuffix); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	s.recorded = false
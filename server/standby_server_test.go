@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/third_party/github.com/goraft/raft"
+
+	"github.com/coreos/etcd/store"
+)
+
+func encodeStandbyInfoForTest(t *testing.T, info standbyInfo) []byte {
+	t.Helper()
+	payload, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("marshal standbyInfo: %v", err)
+	}
+	var trailer [crcTrailerSize]byte
+	binary.BigEndian.PutUint32(trailer[:], crc32.ChecksumIEEE(payload))
+	return append(payload, trailer[:]...)
+}
+
+func TestDecodeStandbyInfoRoundTrip(t *testing.T) {
+	want := standbyInfo{SyncInterval: 5}
+	data := encodeStandbyInfoForTest(t, want)
+
+	got, err := decodeStandbyInfo(data)
+	if err != nil {
+		t.Fatalf("decodeStandbyInfo: %v", err)
+	}
+	if got.SyncInterval != want.SyncInterval {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeStandbyInfoRejectsCorruption(t *testing.T) {
+	data := encodeStandbyInfoForTest(t, standbyInfo{SyncInterval: 5})
+	data[0] ^= 0xFF
+
+	if _, err := decodeStandbyInfo(data); err == nil {
+		t.Fatal("expected checksum verification to fail, got nil error")
+	}
+}
+
+func TestDecodeStandbyInfoRejectsTruncation(t *testing.T) {
+	if _, err := decodeStandbyInfo([]byte("ab")); err == nil {
+		t.Fatal("expected a truncated payload to error, got nil")
+	}
+}
+
+func TestLoadStandbyInfoFallsBackToBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standby-info-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &StandbyServer{Config: StandbyServerConfig{DataDir: dir}}
+
+	good := encodeStandbyInfoForTest(t, standbyInfo{SyncInterval: 7})
+	if err := ioutil.WriteFile(s.standbyInfoPath()+standbyInfoBakSuffix, good, 0600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	corrupt := encodeStandbyInfoForTest(t, standbyInfo{SyncInterval: 9})
+	corrupt[0] ^= 0xFF
+	if err := ioutil.WriteFile(s.standbyInfoPath(), corrupt, 0600); err != nil {
+		t.Fatalf("write primary: %v", err)
+	}
+
+	if _, err := s.loadStandbyInfo(); err != nil {
+		t.Fatalf("loadStandbyInfo: %v", err)
+	}
+	if s.SyncInterval != 7 {
+		t.Fatalf("expected to recover SyncInterval from backup (7), got %v", s.SyncInterval)
+	}
+}
+
+func TestLoadStandbyInfoErrorsWhenBackupAlsoCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standby-info-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &StandbyServer{Config: StandbyServerConfig{DataDir: dir}}
+
+	corrupt := encodeStandbyInfoForTest(t, standbyInfo{SyncInterval: 9})
+	corrupt[0] ^= 0xFF
+	if err := ioutil.WriteFile(s.standbyInfoPath(), corrupt, 0600); err != nil {
+		t.Fatalf("write primary: %v", err)
+	}
+	if err := ioutil.WriteFile(s.standbyInfoPath()+standbyInfoBakSuffix, corrupt, 0600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	if _, err := s.loadStandbyInfo(); err == nil {
+		t.Fatal("expected an error when both primary and backup are corrupt")
+	}
+}
+
+func TestSaveAndLoadStandbyInfoRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standby-info-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &StandbyServer{Config: StandbyServerConfig{DataDir: dir}}
+	if err := s.openStandbyInfo(); err != nil {
+		t.Fatalf("openStandbyInfo: %v", err)
+	}
+
+	s.SyncInterval = 3
+	if err := s.saveStandbyInfo(); err != nil {
+		t.Fatalf("saveStandbyInfo: %v", err)
+	}
+
+	loaded := &StandbyServer{Config: StandbyServerConfig{DataDir: dir}}
+	if _, err := loaded.loadStandbyInfo(); err != nil {
+		t.Fatalf("loadStandbyInfo: %v", err)
+	}
+	if loaded.SyncInterval != 3 {
+		t.Fatalf("got SyncInterval %v, want 3", loaded.SyncInterval)
+	}
+	if _, err := os.Stat(filepath.Join(dir, standbyInfoName)); err != nil {
+		t.Fatalf("expected standby_info on disk: %v", err)
+	}
+}
+
+func newRoutableStandbyServer(proxyMode ProxyMode) *StandbyServer {
+	s := &StandbyServer{localStore: store.New()}
+	s.Config.ProxyMode = proxyMode
+	s.localStoreReady = 1
+	s.Cluster = []*machineMessage{
+		{PeerURL: "http://leader:7001", ClientURL: "http://leader:4001", State: raft.Leader},
+	}
+	return s
+}
+
+func isRedirected(w *httptest.ResponseRecorder) bool {
+	return w.Header().Get("Location") != ""
+}
+
+func TestServeClientRequestRedirectsWrites(t *testing.T) {
+	s := newRoutableStandbyServer(ProxyModeFollower)
+
+	req := httptest.NewRequest("PUT", "/v2/keys/foo", nil)
+	w := httptest.NewRecorder()
+	s.serveClientRequest(w, req)
+
+	if !isRedirected(w) {
+		t.Fatal("expected a write to redirect to the leader")
+	}
+}
+
+func TestServeClientRequestReadonlyRedirectsWatches(t *testing.T) {
+	s := newRoutableStandbyServer(ProxyModeReadonly)
+
+	req := httptest.NewRequest("GET", "/v2/keys/foo?wait=true", nil)
+	w := httptest.NewRecorder()
+	s.serveClientRequest(w, req)
+
+	if !isRedirected(w) {
+		t.Fatal("expected readonly mode to redirect a watch instead of answering from a snapshot")
+	}
+}
+
+func TestServeClientRequestReadonlyAnswersPlainReadsLocally(t *testing.T) {
+	s := newRoutableStandbyServer(ProxyModeReadonly)
+
+	req := httptest.NewRequest("GET", "/v2/keys/foo", nil)
+	w := httptest.NewRecorder()
+	s.serveClientRequest(w, req)
+
+	if isRedirected(w) {
+		t.Fatal("expected a plain read in readonly mode to be answered locally, not redirected")
+	}
+}
+
+func TestServeClientRequestRedirectsBeforeSnapshotIsReady(t *testing.T) {
+	s := newRoutableStandbyServer(ProxyModeFollower)
+	s.localStoreReady = 0
+
+	req := httptest.NewRequest("GET", "/v2/keys/foo", nil)
+	w := httptest.NewRecorder()
+	s.serveClientRequest(w, req)
+
+	if !isRedirected(w) {
+		t.Fatal("expected reads to redirect until the initial snapshot has loaded")
+	}
+}
+
+func TestNextBackoffStaysWithinBounds(t *testing.T) {
+	s := &StandbyServer{standbyInfo: standbyInfo{SyncInterval: 1}}
+	base := time.Second
+
+	for i := 0; i < 50; i++ {
+		next := s.nextBackoff()
+		if next < base {
+			t.Fatalf("iteration %d: backoff %v below base interval %v", i, next, base)
+		}
+		if next > maxMonitorBackoff {
+			t.Fatalf("iteration %d: backoff %v exceeded cap %v", i, next, maxMonitorBackoff)
+		}
+	}
+}
+
+func TestNextBackoffResetsAfterSuccess(t *testing.T) {
+	s := &StandbyServer{standbyInfo: standbyInfo{SyncInterval: 1}}
+	s.backoff = maxMonitorBackoff
+
+	// monitorCluster clears s.backoff to 0 on a successful join.
+	s.backoff = 0
+	next := s.nextBackoff()
+	if next > 3*time.Second {
+		t.Fatalf("backoff %v did not reset to near the base interval after s.backoff was cleared", next)
+	}
+}
+
+func TestHealthyPeerURLsOrdersQuarantinedPeersLast(t *testing.T) {
+	s := &StandbyServer{}
+	s.PeerHealth = map[string]*peerHealth{
+		"http://bad":  {ConsecutiveFailures: quarantinePeerThreshold, QuarantinedUntil: time.Now().Add(time.Minute)},
+		"http://good": {ConsecutiveFailures: 1, QuarantinedUntil: time.Now().Add(-time.Minute)},
+	}
+
+	ordered := s.healthyPeerURLs([]string{"http://bad", "http://good", "http://unknown"})
+	if len(ordered) != 3 {
+		t.Fatalf("expected 3 peers back, got %d: %v", len(ordered), ordered)
+	}
+	if ordered[len(ordered)-1] != "http://bad" {
+		t.Fatalf("expected quarantined peer last, got order %v", ordered)
+	}
+}
+
+func TestHealthyPeerURLsKeepsAllPeersWhenAllQuarantined(t *testing.T) {
+	s := &StandbyServer{}
+	s.PeerHealth = map[string]*peerHealth{
+		"http://a": {QuarantinedUntil: time.Now().Add(time.Minute)},
+		"http://b": {QuarantinedUntil: time.Now().Add(time.Minute)},
+	}
+
+	ordered := s.healthyPeerURLs([]string{"http://a", "http://b"})
+	if len(ordered) != 2 {
+		t.Fatalf("quarantined peers must not be dropped entirely, got %v", ordered)
+	}
+}